@@ -0,0 +1,82 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package common defines the types that are shared between the expander,
+// the deployment manager and its clients.
+package common
+
+// ImportFile names a file that a Template depends on, along with the
+// content of that file. Templates reference their imports by name.
+type ImportFile struct {
+	Name    string
+	Path    string
+	Content string
+}
+
+// Template is the input to an Expander. It is either a single configuration
+// file plus the files it imports, or the result of unpacking a chart
+// archive into the same shape.
+type Template struct {
+	Name    string
+	Content string
+	Imports []*ImportFile
+
+	// Chartfile holds the parsed Chart.yaml for templates that were
+	// created from a chart archive. It is nil for plain templates.
+	Chartfile *Chartfile
+
+	// Schema, if set, names the import that holds the JSON Schema the
+	// template's properties must satisfy.
+	Schema *ImportFile
+
+	// Properties holds the raw YAML properties the caller supplied when
+	// invoking this template, validated against Schema when set.
+	Properties string
+}
+
+// Chartfile describes the Chart.yaml that sits at the root of a chart
+// archive and tells the expander how to expand the chart's templates.
+type Chartfile struct {
+	Name     string
+	Expander ExpanderSpec
+	Schema   string
+}
+
+// ExpanderSpec names the expander that should be used to expand a chart,
+// and the template file within the chart that it should start from.
+type ExpanderSpec struct {
+	Name       string
+	Entrypoint string
+}
+
+// ExpansionRequest is passed to an Expander to describe the template to
+// expand, along with the properties to expand it with.
+type ExpansionRequest struct {
+	Template *Template
+
+	// Type is the name the caller invoked the template as. For chart
+	// archives, it must match Chartfile.Name.
+	Type string
+
+	// Properties holds the raw YAML properties the caller supplied for
+	// this invocation.
+	Properties string
+}
+
+// ExpansionResponse is the result of successfully expanding a Template.
+type ExpansionResponse struct {
+	Config string
+}
@@ -0,0 +1,71 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package expander
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/kubernetes/deployment-manager/common"
+)
+
+// expandybirdBackend expands a template by shelling out to an external
+// expansion program, such as expansion.py, and feeding it the template's
+// content and imports as JSON on stdin.
+type expandybirdBackend struct {
+	ExpanderBinary string
+}
+
+// expansionInput is the JSON document handed to the expander binary on
+// stdin.
+type expansionInput struct {
+	Content string               `json:"content"`
+	Imports []*common.ImportFile `json:"imports"`
+}
+
+func (b *expandybirdBackend) ExpandTemplate(t *common.Template) ([]byte, error) {
+	input := &expansionInput{
+		Content: t.Content,
+		Imports: t.Imports,
+	}
+
+	requestBytes, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal template %s for expansion: %s", t.Name, err)
+	}
+
+	cmd := exec.Command(b.ExpanderBinary)
+	cmd.Stdin = bytes.NewReader(requestBytes)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+
+		return nil, fmt.Errorf("%s", message)
+	}
+
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,371 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package expander turns a template and its imports into the expanded
+// configuration that the deployment manager will actually instantiate.
+package expander
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/kubernetes/deployment-manager/common"
+)
+
+// chartfileName is the name that the archive format reserves for the
+// chart's manifest, modeled on Chart.yaml in the helm/expandybird chart
+// format.
+const chartfileName = "Chart.yaml"
+
+// defaultBackendName is the backend used for templates that were not
+// created from a chart archive, or whose chart does not declare an
+// expander, preserving the historical expandybird-only behavior.
+const defaultBackendName = "expandybird"
+
+// Expander expands a template by dispatching it to the Backend
+// registered under its chart's declared expander name.
+type Expander struct {
+	backends map[string]Backend
+
+	// StrictSchema makes ExpandTemplate validate a template's properties
+	// against its declared schema before expansion, returning a
+	// *SchemaError instead of invoking a backend at all.
+	StrictSchema bool
+}
+
+// NewExpander creates an Expander with the built-in backends registered:
+// "expandybird", which shells out to expanderBinary; "gotemplate", which
+// expands charts with Go's text/template; and "passthrough", which
+// returns a template's content unchanged. When strictSchema is true,
+// templates that declare a schema have their properties validated
+// against it before expansion.
+func NewExpander(expanderBinary string, strictSchema bool) *Expander {
+	e := &Expander{
+		backends:     map[string]Backend{},
+		StrictSchema: strictSchema,
+	}
+
+	e.RegisterBackend(defaultBackendName, &expandybirdBackend{ExpanderBinary: expanderBinary})
+	e.RegisterBackend("gotemplate", &gotemplateBackend{})
+	e.RegisterBackend("passthrough", &passthroughBackend{})
+
+	return e
+}
+
+// Backend expands a template's resolved content and imports into YAML
+// configuration. Charts select a Backend by name via
+// Chart.yaml's `expander.name` field.
+type Backend interface {
+	ExpandTemplate(t *common.Template) ([]byte, error)
+}
+
+// RegisterBackend makes backend available under name, overriding any
+// backend previously registered under that name.
+func (e *Expander) RegisterBackend(name string, backend Backend) {
+	e.backends[name] = backend
+}
+
+// ExpandTemplate expands the given template and returns the resulting
+// configuration as YAML, or an error describing why expansion failed.
+func (e *Expander) ExpandTemplate(t *common.Template) (string, error) {
+	if e.StrictSchema && t.Schema != nil {
+		if err := ValidateProperties(t, []byte(t.Schema.Content)); err != nil {
+			return "", err
+		}
+	}
+
+	name := defaultBackendName
+	if t.Chartfile != nil && t.Chartfile.Expander.Name != "" {
+		name = t.Chartfile.Expander.Name
+	}
+
+	backend, ok := e.backends[name]
+	if !ok {
+		return "", fmt.Errorf("no expander backend registered for %q", name)
+	}
+
+	output, err := backend.ExpandTemplate(t)
+	if err != nil {
+		return "", err
+	}
+
+	return string(output), nil
+}
+
+// ExpandChart expands a template that was created from a chart archive.
+// It verifies that req.Type matches the chart's declared name, resolves
+// the chart's entrypoint and optional schema from the template's
+// imports, and hands both to the expander binary.
+func (e *Expander) ExpandChart(req *common.ExpansionRequest) (*common.ExpansionResponse, error) {
+	t := req.Template
+	if t.Chartfile == nil {
+		return nil, fmt.Errorf("%s: template was not created from a chart archive", req.Type)
+	}
+
+	if req.Type != t.Chartfile.Name {
+		return nil, fmt.Errorf("invocation type %q does not match chart name %q", req.Type, t.Chartfile.Name)
+	}
+
+	entrypoint := findImport(t.Imports, t.Chartfile.Expander.Entrypoint)
+	if entrypoint == nil {
+		return nil, fmt.Errorf("chart %s: entrypoint %q not found in chart archive",
+			t.Chartfile.Name, t.Chartfile.Expander.Entrypoint)
+	}
+
+	var schema *common.ImportFile
+	if t.Chartfile.Schema != "" {
+		schema = findImport(t.Imports, t.Chartfile.Schema)
+		if schema == nil {
+			return nil, fmt.Errorf("chart %s: schema %q is declared in Chart.yaml but not found in chart archive",
+				t.Chartfile.Name, t.Chartfile.Schema)
+		}
+	}
+
+	expanded := &common.Template{
+		Name:       entrypoint.Name,
+		Content:    entrypoint.Content,
+		Imports:    t.Imports,
+		Chartfile:  t.Chartfile,
+		Schema:     schema,
+		Properties: req.Properties,
+	}
+
+	config, err := e.ExpandTemplate(expanded)
+	if err != nil {
+		return nil, err
+	}
+
+	return &common.ExpansionResponse{Config: config}, nil
+}
+
+func findImport(imports []*common.ImportFile, name string) *common.ImportFile {
+	for _, imp := range imports {
+		if imp.Name == name {
+			return imp
+		}
+	}
+
+	return nil
+}
+
+// NewTemplateFromReader reads a single template file, along with the
+// files it imports, from r.
+func NewTemplateFromReader(name string, r io.Reader, importFileNames []string) (*common.Template, error) {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read template %s: %s", name, err)
+	}
+
+	if len(content) == 0 {
+		return nil, fmt.Errorf("template %s is empty", name)
+	}
+
+	imports, err := importFiles(importFileNames)
+	if err != nil {
+		return nil, err
+	}
+
+	return &common.Template{
+		Name:    name,
+		Content: string(content),
+		Imports: imports,
+	}, nil
+}
+
+// NewTemplateFromFileNames reads a template and its imports from disk.
+func NewTemplateFromFileNames(templateFileName string, importFileNames []string) (*common.Template, error) {
+	file, err := os.Open(templateFileName)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open template %s: %s", templateFileName, err)
+	}
+	defer file.Close()
+
+	return NewTemplateFromReader(path.Base(templateFileName), file, importFileNames)
+}
+
+func importFiles(importFileNames []string) ([]*common.ImportFile, error) {
+	imports := []*common.ImportFile{}
+	for _, importFileName := range importFileNames {
+		content, err := ioutil.ReadFile(importFileName)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read import file %s: %s", importFileName, err)
+		}
+
+		imports = append(imports, &common.ImportFile{
+			Name:    path.Base(importFileName),
+			Path:    importFileName,
+			Content: string(content),
+		})
+	}
+
+	return imports, nil
+}
+
+// gzipMagic is the two leading bytes of a gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// readArchive reads every member of the tar archive in r into memory. r
+// may be a plain tar stream or a gzip-wrapped one, as produced by
+// `.tgz` chart archives; readArchive sniffs the leading bytes to tell
+// them apart and does not require r to support seeking.
+func readArchive(r io.Reader) (map[string]string, error) {
+	buffered := bufio.NewReader(r)
+
+	magic, err := buffered.Peek(len(gzipMagic))
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("cannot read archive: %s", err)
+	}
+
+	var tr *tar.Reader
+	if bytes.Equal(magic, gzipMagic) {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read gzip archive: %s", err)
+		}
+		defer gz.Close()
+
+		tr = tar.NewReader(gz)
+	} else {
+		tr = tar.NewReader(buffered)
+	}
+
+	entries := map[string]string{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("cannot read archive: %s", err)
+		}
+
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read %s from archive: %s", header.Name, err)
+		}
+
+		entries[header.Name] = string(content)
+	}
+
+	return entries, nil
+}
+
+// NewTemplateFromArchive reads name out of the tar archive in r as the
+// template content, and every other member of the archive as an import.
+func NewTemplateFromArchive(name string, r io.Reader, importFileNames []string) (*common.Template, error) {
+	entries, err := readArchive(r)
+	if err != nil {
+		return nil, err
+	}
+
+	content, ok := entries[name]
+	if !ok {
+		return nil, fmt.Errorf("cannot find %s in archive", name)
+	}
+
+	if len(content) == 0 {
+		return nil, fmt.Errorf("%s is empty in archive", name)
+	}
+
+	imports, err := importFiles(importFileNames)
+	if err != nil {
+		return nil, err
+	}
+
+	for entryName, entryContent := range entries {
+		if entryName == name {
+			continue
+		}
+
+		imports = append(imports, &common.ImportFile{Name: entryName, Content: entryContent})
+	}
+
+	return &common.Template{
+		Name:    name,
+		Content: content,
+		Imports: imports,
+	}, nil
+}
+
+// NewTemplateFromChartArchive reads a chart archive, in the format
+// produced by the helm/expandybird chart tooling, out of r. The archive
+// must contain a Chart.yaml; every other member becomes an import that
+// ExpandChart resolves the entrypoint and schema from.
+func NewTemplateFromChartArchive(r io.Reader) (*common.Template, error) {
+	entries, err := readArchive(r)
+	if err != nil {
+		return nil, err
+	}
+
+	chartfileContent, ok := entries[chartfileName]
+	if !ok {
+		return nil, fmt.Errorf("chart archive does not contain %s", chartfileName)
+	}
+
+	chartfile := &common.Chartfile{}
+	if err := yaml.Unmarshal([]byte(chartfileContent), chartfile); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %s", chartfileName, err)
+	}
+
+	if chartfile.Name == "" {
+		return nil, fmt.Errorf("%s does not declare a chart name", chartfileName)
+	}
+
+	imports := []*common.ImportFile{}
+	for entryName, entryContent := range entries {
+		if entryName == chartfileName {
+			continue
+		}
+
+		imports = append(imports, &common.ImportFile{Name: entryName, Content: entryContent})
+	}
+
+	return &common.Template{
+		Name:      chartfile.Name,
+		Imports:   imports,
+		Chartfile: chartfile,
+	}, nil
+}
+
+// ExpansionResult is the parsed form of an expander's YAML output.
+type ExpansionResult struct {
+	Resources []interface{} `yaml:"resources"`
+}
+
+// NewExpansionResult parses the YAML configuration produced by an
+// Expander into an ExpansionResult for comparison in tests.
+func NewExpansionResult(output string) (*ExpansionResult, error) {
+	result := &ExpansionResult{}
+	if err := yaml.Unmarshal([]byte(output), result); err != nil {
+		return nil, fmt.Errorf("cannot parse expansion output: %s", err)
+	}
+
+	return result, nil
+}
@@ -19,6 +19,7 @@ package expander
 import (
 	"archive/tar"
 	"bytes"
+	"compress/gzip"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -97,7 +98,7 @@ func testExpandTemplateFromFile(t *testing.T, fileName, baseName string, importF
 		t.Fatalf("cannot create template from file %s: %s", fileName, err)
 	}
 
-	backend := NewExpander(expanderName)
+	backend := NewExpander(expanderName, false)
 	actualOutput, err := backend.ExpandTemplate(template)
 	if err != nil {
 		t.Fatalf("cannot expand template from file %s: %s", fileName, err)
@@ -133,8 +134,23 @@ var validFiles = archiveBuilder{
 }
 
 func generateArchive(t *testing.T, files archiveBuilder) *bytes.Reader {
+	return bytes.NewReader(generateArchiveBytes(t, files, false))
+}
+
+// generateArchiveBytes builds a tar archive from files, optionally
+// wrapping it in gzip to exercise the `.tgz` chart archive path.
+func generateArchiveBytes(t *testing.T, files archiveBuilder, gzipped bool) []byte {
 	buffer := new(bytes.Buffer)
-	tw := tar.NewWriter(buffer)
+
+	var tw *tar.Writer
+	var gz *gzip.Writer
+	if gzipped {
+		gz = gzip.NewWriter(buffer)
+		tw = tar.NewWriter(gz)
+	} else {
+		tw = tar.NewWriter(buffer)
+	}
+
 	for _, file := range files {
 		hdr := &tar.Header{
 			Name: file.Name,
@@ -155,8 +171,13 @@ func generateArchive(t *testing.T, files archiveBuilder) *bytes.Reader {
 		t.Fatal(err)
 	}
 
-	r := bytes.NewReader(buffer.Bytes())
-	return r
+	if gzipped {
+		if err := gz.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return buffer.Bytes()
 }
 
 func TestNewTemplateFromArchive(t *testing.T) {
@@ -186,6 +207,30 @@ func TestNewTemplateFromArchive(t *testing.T) {
 	}
 }
 
+func TestNewTemplateFromArchive_Gzip(t *testing.T) {
+	uncompressed := generateArchiveBytes(t, validFiles, false)
+	template, err := NewTemplateFromArchive(validFiles[1].Name, bytes.NewReader(uncompressed), nil)
+	if err != nil {
+		t.Fatalf("cannot create template from uncompressed archive: %s", err)
+	}
+
+	gzipped := generateArchiveBytes(t, validFiles, true)
+	gzippedTemplate, err := NewTemplateFromArchive(validFiles[1].Name, bytes.NewReader(gzipped), nil)
+	if err != nil {
+		t.Fatalf("cannot create template from gzipped archive: %s", err)
+	}
+
+	if !reflect.DeepEqual(template, gzippedTemplate) {
+		t.Fatalf("gzipped archive produced a different template than its uncompressed equivalent:\nwant:\n%+v\nhave:\n%+v\n",
+			template, gzippedTemplate)
+	}
+
+	truncated := gzipped[:4]
+	if _, err := NewTemplateFromArchive(validFiles[1].Name, bytes.NewReader(truncated), nil); err == nil {
+		t.Fatalf("expected error did not occur for truncated gzip archive")
+	}
+}
+
 func TestNewTemplateFromFileNames(t *testing.T) {
 	if _, err := NewTemplateFromFileNames(invalidFileName, importFileNames); err == nil {
 		t.Fatalf("expected error did not occur for invalid template file name")
@@ -253,7 +298,7 @@ var ExpanderTestCases = []ExpanderTestCase{
 }
 
 func TestExpandTemplate(t *testing.T) {
-	backend := NewExpander(expanderName)
+	backend := NewExpander(expanderName, false)
 	for _, etc := range ExpanderTestCases {
 		template := etc.GetTemplate(t)
 		actualOutput, err := backend.ExpandTemplate(template)
@@ -272,3 +317,218 @@ func TestExpandTemplate(t *testing.T) {
 		}
 	}
 }
+
+type ChartExpanderTestCase struct {
+	Description    string
+	ChartYAML      string
+	Files          archiveBuilder
+	InvocationType string
+	ExpectedError  string
+}
+
+var ChartExpanderTestCases = []ChartExpanderTestCase{
+	{
+		"expect error for mismatched invocation type",
+		"name: replicatedservice\nexpander:\n  name: expandybird\n  entrypoint: replicatedservice.py\n",
+		archiveBuilder{{"replicatedservice.py", "# entrypoint"}},
+		"wrongtype",
+		`invocation type "wrongtype" does not match chart name "replicatedservice"`,
+	},
+	{
+		"expect error for missing entrypoint",
+		"name: replicatedservice\nexpander:\n  name: expandybird\n  entrypoint: missing.py\n",
+		archiveBuilder{{"replicatedservice.py", "# entrypoint"}},
+		"replicatedservice",
+		`entrypoint "missing.py" not found in chart archive`,
+	},
+	{
+		"expect error for declared but absent schema",
+		"name: replicatedservice\nexpander:\n  name: expandybird\n  entrypoint: replicatedservice.py\nschema: replicatedservice.schema\n",
+		archiveBuilder{{"replicatedservice.py", "# entrypoint"}},
+		"replicatedservice",
+		`schema "replicatedservice.schema" is declared in Chart.yaml but not found in chart archive`,
+	},
+}
+
+func TestExpandChart(t *testing.T) {
+	backend := NewExpander(expanderName, false)
+	for _, tc := range ChartExpanderTestCases {
+		files := append(archiveBuilder{{"Chart.yaml", tc.ChartYAML}}, tc.Files...)
+		r := generateArchive(t, files)
+
+		template, err := NewTemplateFromChartArchive(r)
+		if err != nil {
+			t.Fatalf("cannot create template for test case '%s': %s", tc.Description, err)
+		}
+
+		req := &common.ExpansionRequest{Template: template, Type: tc.InvocationType}
+		if _, err := backend.ExpandChart(req); err == nil {
+			t.Fatalf("expected error did not occur in test case '%s'", tc.Description)
+		} else if !strings.Contains(err.Error(), tc.ExpectedError) {
+			t.Fatalf("error in test case '%s': %s", tc.Description, err.Error())
+		}
+	}
+}
+
+type BackendTestCase struct {
+	Description   string
+	BackendName   string
+	Content       string
+	Properties    string
+	ExpectedError string
+	ExpectedValue string
+}
+
+var BackendTestCases = []BackendTestCase{
+	{
+		"gotemplate expands properties into the entrypoint",
+		"gotemplate",
+		"resources:\n- name: {{ .name }}\n  type: Service\n  properties:\n    port: {{ default 80 .port }}\n",
+		"name: test-service\n",
+		"",
+		"resources:\n- name: test-service\n  type: Service\n  properties:\n    port: 80\n",
+	},
+	{
+		"gotemplate reports a parse error for malformed templates",
+		"gotemplate",
+		"resources: {{ .broken",
+		"",
+		"cannot parse template",
+		"",
+	},
+	{
+		"passthrough returns the entrypoint unchanged",
+		"passthrough",
+		"resources:\n- name: test-service\n  type: Service\n",
+		"",
+		"",
+		"resources:\n- name: test-service\n  type: Service\n",
+	},
+}
+
+func TestBackends(t *testing.T) {
+	backend := NewExpander(expanderName, false)
+	for _, tc := range BackendTestCases {
+		template := &common.Template{
+			Name:       "test",
+			Content:    tc.Content,
+			Properties: tc.Properties,
+			Chartfile: &common.Chartfile{
+				Name:     "test",
+				Expander: common.ExpanderSpec{Name: tc.BackendName},
+			},
+		}
+
+		output, err := backend.ExpandTemplate(template)
+		if tc.ExpectedError != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.ExpectedError) {
+				t.Fatalf("error in test case '%s': %v", tc.Description, err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("cannot expand template in test case '%s': %s", tc.Description, err)
+		}
+
+		if output != tc.ExpectedValue {
+			t.Fatalf("error in test case '%s': want:\n%s\nhave:\n%s\n", tc.Description, tc.ExpectedValue, output)
+		}
+	}
+}
+
+type SchemaTestCase struct {
+	Description     string
+	Schema          string
+	Properties      string
+	ExpectViolation string
+}
+
+var SchemaTestCases = []SchemaTestCase{
+	{
+		"expect error for missing required property",
+		`{"type":"object","required":["image"],"properties":{"image":{"type":"string"}}}`,
+		"replicas: 3\n",
+		"image",
+	},
+	{
+		"expect error for wrong type",
+		`{"type":"object","properties":{"replicas":{"type":"integer"}}}`,
+		"replicas: \"three\"\n",
+		"replicas",
+	},
+	{
+		"expect error for additional properties",
+		`{"type":"object","additionalProperties":false,"properties":{"image":{"type":"string"}}}`,
+		"image: nginx\nbogus: true\n",
+		"bogus",
+	},
+	{
+		"expect success for valid properties",
+		`{"type":"object","properties":{"image":{"type":"string"}}}`,
+		"image: nginx\n",
+		"",
+	},
+}
+
+func TestValidateProperties(t *testing.T) {
+	for _, tc := range SchemaTestCases {
+		template := &common.Template{Name: "test", Properties: tc.Properties}
+		err := ValidateProperties(template, []byte(tc.Schema))
+
+		if tc.ExpectViolation == "" {
+			if err != nil {
+				t.Fatalf("unexpected error in test case '%s': %s", tc.Description, err)
+			}
+
+			continue
+		}
+
+		if err == nil {
+			t.Fatalf("expected error did not occur in test case '%s'", tc.Description)
+		}
+
+		if !strings.Contains(err.Error(), tc.ExpectViolation) {
+			t.Fatalf("error in test case '%s': %s", tc.Description, err.Error())
+		}
+	}
+}
+
+// TestExpandTemplateStrictSchema locks down the StrictSchema wiring
+// itself: ExpandTemplate must reject properties that violate the
+// template's schema before a backend ever sees them, and must expand
+// normally once the properties are fixed up.
+func TestExpandTemplateStrictSchema(t *testing.T) {
+	backend := NewExpander(expanderName, true)
+
+	template := &common.Template{
+		Name:       "test",
+		Content:    "resources:\n- name: test-service\n  type: Service\n",
+		Properties: "replicas: 3\n",
+		Chartfile: &common.Chartfile{
+			Name:     "test",
+			Expander: common.ExpanderSpec{Name: "passthrough"},
+		},
+		Schema: &common.ImportFile{
+			Name:    "schema.json",
+			Content: `{"type":"object","required":["image"],"properties":{"image":{"type":"string"}}}`,
+		},
+	}
+
+	if _, err := backend.ExpandTemplate(template); err == nil {
+		t.Fatalf("expected schema violation did not occur")
+	} else if !strings.Contains(err.Error(), "image") {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	template.Properties = "image: nginx\n"
+	output, err := backend.ExpandTemplate(template)
+	if err != nil {
+		t.Fatalf("cannot expand template with valid properties: %s", err)
+	}
+
+	if output != template.Content {
+		t.Fatalf("want:\n%s\nhave:\n%s\n", template.Content, output)
+	}
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package expander
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kubernetes/deployment-manager/common"
+)
+
+// repoAliases maps a short repo name to the base URL it resolves
+// against, so callers can write "stable/replicatedservice" instead of a
+// full URL, mirroring helm's mapRepoArg.
+var repoAliases = map[string]string{
+	"stable": "https://kubernetes-charts.storage.googleapis.com",
+}
+
+// RegisterRepoAlias makes name resolve to baseURL when FetchTemplate is
+// given a "name/chart" argument instead of a full URL.
+func RegisterRepoAlias(name, baseURL string) {
+	repoAliases[name] = baseURL
+}
+
+// FetchOptions controls how FetchTemplate retrieves and unpacks a
+// remote archive.
+type FetchOptions struct {
+	// Digest, when set, must match the hex-encoded SHA-256 of the
+	// downloaded archive, or FetchTemplate fails.
+	Digest string
+
+	// UntarDir, when set, expands the archive onto disk under this
+	// directory and loads the template from there. When empty, the
+	// archive is read directly into a Template in memory.
+	UntarDir string
+}
+
+// FetchTemplate downloads url, or a "repo/chart" argument resolved
+// through the repo alias map, and turns the resulting archive into a
+// Template.
+func FetchTemplate(url string, opts FetchOptions) (*common.Template, error) {
+	url = mapRepoArg(url)
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("cannot fetch %s: %s", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("cannot fetch %s: %s", url, resp.Status)
+	}
+
+	archive, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s: %s", url, err)
+	}
+
+	if opts.Digest != "" {
+		sum := sha256.Sum256(archive)
+		if actual := hex.EncodeToString(sum[:]); actual != opts.Digest {
+			return nil, fmt.Errorf("%s has digest %s, want %s", url, actual, opts.Digest)
+		}
+	}
+
+	if opts.UntarDir == "" {
+		return NewTemplateFromChartArchive(bytes.NewReader(archive))
+	}
+
+	return untarChartArchive(archive, opts.UntarDir)
+}
+
+// mapRepoArg resolves a "repo/chart" argument to a full URL using
+// repoAliases, leaving url untouched if its first path segment is not a
+// known repo alias.
+func mapRepoArg(url string) string {
+	parts := strings.SplitN(url, "/", 2)
+	if len(parts) != 2 {
+		return url
+	}
+
+	baseURL, ok := repoAliases[parts[0]]
+	if !ok {
+		return url
+	}
+
+	return strings.TrimSuffix(baseURL, "/") + "/" + parts[1]
+}
+
+// untarChartArchive expands archive onto disk under dir, rejecting any
+// member whose name would escape dir, then loads the result as a chart
+// template the same way NewTemplateFromChartArchive would from memory.
+func untarChartArchive(archive []byte, dir string) (*common.Template, error) {
+	entries, err := readArchive(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("cannot unpack archive: %s", err)
+	}
+
+	for entryName, content := range entries {
+		fileName, err := safeExtractPath(dir, entryName)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := os.MkdirAll(filepath.Dir(fileName), 0755); err != nil {
+			return nil, fmt.Errorf("cannot create %s: %s", filepath.Dir(fileName), err)
+		}
+
+		if err := ioutil.WriteFile(fileName, []byte(content), 0644); err != nil {
+			return nil, fmt.Errorf("cannot write %s: %s", fileName, err)
+		}
+	}
+
+	return NewTemplateFromChartArchive(bytes.NewReader(archive))
+}
+
+// safeExtractPath joins dir and entryName, the way any tar/zip extractor
+// must, rejecting absolute paths and ".." segments that would let an
+// archive member write outside dir (a "tar-slip" vulnerability).
+func safeExtractPath(dir, entryName string) (string, error) {
+	if filepath.IsAbs(entryName) {
+		return "", fmt.Errorf("refusing to extract archive entry with absolute path %q", entryName)
+	}
+
+	fileName := filepath.Join(dir, entryName)
+	cleanedDir := filepath.Clean(dir)
+	if fileName != cleanedDir && !strings.HasPrefix(fileName, cleanedDir+string(filepath.Separator)) {
+		return "", fmt.Errorf("refusing to extract archive entry %q outside of %s", entryName, dir)
+	}
+
+	return fileName, nil
+}
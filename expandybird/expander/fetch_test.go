@@ -0,0 +1,131 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package expander
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const fetchChartYAML = "name: replicatedservice\nexpander:\n  name: expandybird\n  entrypoint: replicatedservice.py\n"
+
+var fetchFiles = archiveBuilder{
+	{"Chart.yaml", fetchChartYAML},
+	{"replicatedservice.py", "# entrypoint"},
+}
+
+func newFetchServer(t *testing.T, archive []byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing.tar" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if _, err := w.Write(archive); err != nil {
+			t.Fatalf("cannot write response: %s", err)
+		}
+	}))
+}
+
+func TestFetchTemplate(t *testing.T) {
+	archive := generateArchiveBytes(t, fetchFiles, false)
+	server := newFetchServer(t, archive)
+	defer server.Close()
+
+	if _, err := FetchTemplate(server.URL+"/missing.tar", FetchOptions{}); err == nil {
+		t.Fatalf("expected error did not occur for 404 response")
+	}
+
+	sum := sha256.Sum256(archive)
+	digest := hex.EncodeToString(sum[:])
+
+	if _, err := FetchTemplate(server.URL+"/replicatedservice.tar", FetchOptions{Digest: "deadbeef"}); err == nil {
+		t.Fatalf("expected error did not occur for digest mismatch")
+	}
+
+	template, err := FetchTemplate(server.URL+"/replicatedservice.tar", FetchOptions{Digest: digest})
+	if err != nil {
+		t.Fatalf("cannot fetch template: %s", err)
+	}
+
+	if template.Chartfile == nil || template.Chartfile.Name != "replicatedservice" {
+		t.Fatalf("expected a chart named replicatedservice, got %+v", template.Chartfile)
+	}
+
+	entrypoint := findImport(template.Imports, template.Chartfile.Expander.Entrypoint)
+	if entrypoint == nil || entrypoint.Content != "# entrypoint" {
+		t.Fatalf("expected entrypoint replicatedservice.py to be an import, got %+v", template.Imports)
+	}
+
+	dir, err := ioutil.TempDir("", "fetch-test")
+	if err != nil {
+		t.Fatalf("cannot create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	untarred, err := FetchTemplate(server.URL+"/replicatedservice.tar", FetchOptions{UntarDir: dir})
+	if err != nil {
+		t.Fatalf("cannot fetch and untar template: %s", err)
+	}
+
+	if untarred.Chartfile == nil || untarred.Chartfile.Name != "replicatedservice" {
+		t.Fatalf("expected a chart named replicatedservice, got %+v", untarred.Chartfile)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "Chart.yaml")); err != nil {
+		t.Fatalf("expected Chart.yaml on disk: %s", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "replicatedservice.py")); err != nil {
+		t.Fatalf("expected replicatedservice.py on disk: %s", err)
+	}
+}
+
+func TestFetchTemplate_PathTraversal(t *testing.T) {
+	escapeTarget := filepath.Join(os.TempDir(), "expander-fetch-traversal-pwned")
+	os.Remove(escapeTarget)
+	defer os.Remove(escapeTarget)
+
+	maliciousFiles := archiveBuilder{
+		{"Chart.yaml", fetchChartYAML},
+		{"../expander-fetch-traversal-pwned", "pwned"},
+	}
+	archive := generateArchiveBytes(t, maliciousFiles, false)
+
+	server := newFetchServer(t, archive)
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "fetch-traversal-test")
+	if err != nil {
+		t.Fatalf("cannot create temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if _, err := FetchTemplate(server.URL+"/chart.tar", FetchOptions{UntarDir: dir}); err == nil {
+		t.Fatalf("expected error did not occur for archive entry outside UntarDir")
+	}
+
+	if _, err := os.Stat(escapeTarget); err == nil {
+		t.Fatalf("archive entry escaped UntarDir and was written to %s", escapeTarget)
+	}
+}
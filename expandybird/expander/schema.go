@@ -0,0 +1,120 @@
+/*
+Copyright 2015 The Kubernetes Authors All rights reserved.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package expander
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+	"gopkg.in/yaml.v2"
+
+	"github.com/kubernetes/deployment-manager/common"
+)
+
+// SchemaViolation describes a single way in which a template's
+// properties failed to satisfy its schema.
+type SchemaViolation struct {
+	Path    string
+	Message string
+}
+
+// SchemaError is returned by ValidateProperties when a template's
+// properties do not satisfy its declared JSON Schema. It reports every
+// violation found, not just the first, so callers can surface a
+// deterministic, structured error instead of a Python traceback.
+type SchemaError struct {
+	Violations []SchemaViolation
+}
+
+func (e *SchemaError) Error() string {
+	messages := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		messages[i] = fmt.Sprintf("%s: %s", violation.Path, violation.Message)
+	}
+
+	return fmt.Sprintf("properties do not satisfy schema: %s", strings.Join(messages, "; "))
+}
+
+// ValidateProperties validates template's properties against the given
+// JSON Schema (Draft 4). It returns nil if the properties satisfy the
+// schema, and a *SchemaError otherwise.
+func ValidateProperties(template *common.Template, schemaBytes []byte) error {
+	schemaDoc, err := yamlToJSONLoader(schemaBytes)
+	if err != nil {
+		return fmt.Errorf("cannot parse schema for %s: %s", template.Name, err)
+	}
+
+	propertiesDoc, err := yamlToJSONLoader([]byte(template.Properties))
+	if err != nil {
+		return fmt.Errorf("cannot parse properties for %s: %s", template.Name, err)
+	}
+
+	result, err := gojsonschema.Validate(schemaDoc, propertiesDoc)
+	if err != nil {
+		return fmt.Errorf("cannot validate properties for %s: %s", template.Name, err)
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	violations := make([]SchemaViolation, len(result.Errors()))
+	for i, resultError := range result.Errors() {
+		violations[i] = SchemaViolation{Path: resultError.Field(), Message: resultError.Description()}
+	}
+
+	return &SchemaError{Violations: violations}
+}
+
+// yamlToJSONLoader parses raw (possibly empty) YAML into a generic
+// document that gojsonschema can validate, since YAML's map keys don't
+// satisfy the JSON-compatible types gojsonschema expects.
+func yamlToJSONLoader(raw []byte) (gojsonschema.JSONLoader, error) {
+	var doc interface{}
+	if len(strings.TrimSpace(string(raw))) > 0 {
+		if err := yaml.Unmarshal(raw, &doc); err != nil {
+			return nil, err
+		}
+	}
+
+	return gojsonschema.NewGoLoader(normalizeYAML(doc)), nil
+}
+
+// normalizeYAML recursively converts the map[interface{}]interface{}
+// values that gopkg.in/yaml.v2 produces into map[string]interface{}, so
+// the result can be marshaled to JSON.
+func normalizeYAML(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		normalized := make(map[string]interface{}, len(v))
+		for key, val := range v {
+			normalized[fmt.Sprintf("%v", key)] = normalizeYAML(val)
+		}
+
+		return normalized
+	case []interface{}:
+		normalized := make([]interface{}, len(v))
+		for i, val := range v {
+			normalized[i] = normalizeYAML(val)
+		}
+
+		return normalized
+	default:
+		return v
+	}
+}